@@ -22,6 +22,9 @@ var AppSharedDir syncs.AtomicValue[string]
 // DefaultTailscaledSocket returns the path to the tailscaled Unix socket
 // or the empty string if there's no reasonable default.
 func DefaultTailscaledSocket() string {
+	if v := os.Getenv("TAILSCALED_SOCKET"); v != "" {
+		return v
+	}
 	if runtime.GOOS == "windows" {
 		return `\\.\pipe\ProtectedPrefix\Administrators\Tailscale\tailscaled`
 	}
@@ -40,12 +43,36 @@ func DefaultTailscaledSocket() string {
 	case distro.QNAP:
 		return "/tmp/tailscale/tailscaled.sock"
 	}
+	// RUNTIME_DIRECTORY is set by systemd units with RuntimeDirectory=,
+	// and is the blessed place to put a socket for a service running
+	// under systemd.
+	if d := os.Getenv("RUNTIME_DIRECTORY"); d != "" {
+		return filepath.Join(d, "tailscaled.sock")
+	}
+	// XDG_RUNTIME_DIR is the rootless/user-mode equivalent, for a
+	// tailscaled run out of a user systemd unit or similar.
+	if d := os.Getenv("XDG_RUNTIME_DIR"); d != "" {
+		return filepath.Join(d, "tailscale", "tailscaled.sock")
+	}
 	if fi, err := os.Stat("/var/run"); err == nil && fi.IsDir() {
 		return "/var/run/tailscale/tailscaled.sock"
 	}
 	return "tailscaled.sock"
 }
 
+// UserTailscaledSocket returns the path to a per-UID tailscaled Unix socket
+// for an unprivileged, user-mode tailscaled instance, or the empty string
+// if there's no reasonable default (for example, because XDG_RUNTIME_DIR
+// isn't set). This lets a user-mode tailscaled coexist with a system-wide
+// one at its own DefaultTailscaledSocket path.
+func UserTailscaledSocket() string {
+	d := os.Getenv("XDG_RUNTIME_DIR")
+	if d == "" {
+		return ""
+	}
+	return filepath.Join(d, "tailscale", "tailscaled.sock")
+}
+
 var stateFileFunc func() string
 
 // DefaultTailscaledStateFile returns the default path to the
@@ -55,9 +82,20 @@ func DefaultTailscaledStateFile() string {
 	if f := stateFileFunc; f != nil {
 		return f()
 	}
+	if v := os.Getenv("TAILSCALED_STATE_FILE"); v != "" {
+		return v
+	}
 	if runtime.GOOS == "windows" {
 		return filepath.Join(os.Getenv("ProgramData"), "Tailscale", "server-state.conf")
 	}
+	// STATE_DIRECTORY is set by systemd units with StateDirectory=.
+	if d := os.Getenv("STATE_DIRECTORY"); d != "" {
+		return filepath.Join(d, "tailscaled.state")
+	}
+	// XDG_STATE_HOME is the rootless/user-mode equivalent.
+	if d := os.Getenv("XDG_STATE_HOME"); d != "" {
+		return filepath.Join(d, "tailscale", "tailscaled.state")
+	}
 	return ""
 }
 
@@ -68,6 +106,22 @@ func MkStateDir(dirPath string) error {
 	if err := os.MkdirAll(dirPath, 0700); err != nil {
 		return err
 	}
-
+	if systemdOwnsDir(dirPath) {
+		// systemd already created dirPath per RuntimeDirectory=/
+		// StateDirectory= with the correct owner, and may have set a
+		// group-readable mode on purpose; don't clobber that.
+		return nil
+	}
 	return ensureStateDirPerms(dirPath)
 }
+
+// systemdOwnsDir reports whether dirPath is the RuntimeDirectory= or
+// StateDirectory= that systemd created for this unit.
+func systemdOwnsDir(dirPath string) bool {
+	for _, env := range [...]string{"RUNTIME_DIRECTORY", "STATE_DIRECTORY"} {
+		if d := os.Getenv(env); d != "" && d == dirPath {
+			return true
+		}
+	}
+	return false
+}
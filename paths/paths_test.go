@@ -0,0 +1,134 @@
+// Copyright (c) 2020 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package paths
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func clearPathEnv(t *testing.T) {
+	t.Helper()
+	for _, env := range []string{
+		"TAILSCALED_SOCKET", "TAILSCALED_STATE_FILE",
+		"RUNTIME_DIRECTORY", "STATE_DIRECTORY",
+		"XDG_RUNTIME_DIR", "XDG_STATE_HOME",
+	} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestDefaultTailscaledSocketEnvPrecedence(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skipf("test assumes the generic Linux-ish fallback chain, not applicable on %s", runtime.GOOS)
+	}
+
+	t.Run("explicit env wins over everything", func(t *testing.T) {
+		clearPathEnv(t)
+		t.Setenv("TAILSCALED_SOCKET", "/explicit/tailscaled.sock")
+		t.Setenv("RUNTIME_DIRECTORY", "/run/tailscale")
+		t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+		if got, want := DefaultTailscaledSocket(), "/explicit/tailscaled.sock"; got != want {
+			t.Errorf("DefaultTailscaledSocket() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("RUNTIME_DIRECTORY wins over XDG_RUNTIME_DIR", func(t *testing.T) {
+		clearPathEnv(t)
+		t.Setenv("RUNTIME_DIRECTORY", "/run/tailscale")
+		t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+		want := filepath.Join("/run/tailscale", "tailscaled.sock")
+		if got := DefaultTailscaledSocket(); got != want {
+			t.Errorf("DefaultTailscaledSocket() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("XDG_RUNTIME_DIR used when no systemd dir", func(t *testing.T) {
+		clearPathEnv(t)
+		t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+		want := filepath.Join("/run/user/1000", "tailscale", "tailscaled.sock")
+		if got := DefaultTailscaledSocket(); got != want {
+			t.Errorf("DefaultTailscaledSocket() = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestDefaultTailscaledStateFileEnvPrecedence(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test assumes the non-Windows fallback chain")
+	}
+
+	t.Run("explicit env wins over everything", func(t *testing.T) {
+		clearPathEnv(t)
+		t.Setenv("TAILSCALED_STATE_FILE", "/explicit/state")
+		t.Setenv("STATE_DIRECTORY", "/var/lib/tailscale")
+		t.Setenv("XDG_STATE_HOME", "/home/user/.local/state")
+		if got, want := DefaultTailscaledStateFile(), "/explicit/state"; got != want {
+			t.Errorf("DefaultTailscaledStateFile() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("STATE_DIRECTORY wins over XDG_STATE_HOME", func(t *testing.T) {
+		clearPathEnv(t)
+		t.Setenv("STATE_DIRECTORY", "/var/lib/tailscale")
+		t.Setenv("XDG_STATE_HOME", "/home/user/.local/state")
+		want := filepath.Join("/var/lib/tailscale", "tailscaled.state")
+		if got := DefaultTailscaledStateFile(); got != want {
+			t.Errorf("DefaultTailscaledStateFile() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("XDG_STATE_HOME used when no systemd dir", func(t *testing.T) {
+		clearPathEnv(t)
+		t.Setenv("XDG_STATE_HOME", "/home/user/.local/state")
+		want := filepath.Join("/home/user/.local/state", "tailscale", "tailscaled.state")
+		if got := DefaultTailscaledStateFile(); got != want {
+			t.Errorf("DefaultTailscaledStateFile() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("no env falls back to empty", func(t *testing.T) {
+		clearPathEnv(t)
+		if got := DefaultTailscaledStateFile(); got != "" {
+			t.Errorf("DefaultTailscaledStateFile() = %q, want empty", got)
+		}
+	})
+}
+
+func TestUserTailscaledSocket(t *testing.T) {
+	clearPathEnv(t)
+	if got := UserTailscaledSocket(); got != "" {
+		t.Errorf("UserTailscaledSocket() with no XDG_RUNTIME_DIR = %q, want empty", got)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	want := filepath.Join("/run/user/1000", "tailscale", "tailscaled.sock")
+	if got := UserTailscaledSocket(); got != want {
+		t.Errorf("UserTailscaledSocket() = %q, want %q", got, want)
+	}
+}
+
+func TestSystemdOwnsDir(t *testing.T) {
+	clearPathEnv(t)
+	t.Setenv("RUNTIME_DIRECTORY", "/run/tailscale")
+	if !systemdOwnsDir("/run/tailscale") {
+		t.Error("systemdOwnsDir didn't recognize RUNTIME_DIRECTORY match")
+	}
+	if systemdOwnsDir("/some/other/dir") {
+		t.Error("systemdOwnsDir matched an unrelated directory")
+	}
+
+	clearPathEnv(t)
+	t.Setenv("STATE_DIRECTORY", "/var/lib/tailscale")
+	if !systemdOwnsDir("/var/lib/tailscale") {
+		t.Error("systemdOwnsDir didn't recognize STATE_DIRECTORY match")
+	}
+
+	clearPathEnv(t)
+	if systemdOwnsDir("/var/lib/tailscale") {
+		t.Error("systemdOwnsDir matched with no systemd env vars set")
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2022 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package views
+
+// Builder and MapBuilder provide O(log n), structurally-shared incremental
+// updates via their With* methods. Their Freeze methods, however, are O(n):
+// they flatten the trie/tree into a fresh Slice/Map on every call, so the
+// win from incremental With* calls is only realized if a caller batches
+// several of them before a single Freeze, not if it calls Freeze after
+// every With*. See each Freeze's doc comment for specifics.
+
+// Builder is a persistent, copy-on-write vector. Each With* method returns a
+// new Builder in O(log n) time, sharing every subtree untouched by the
+// change with the receiver, instead of cloning the whole backing slice the
+// way converting a SliceView to a []T, mutating it, and re-wrapping it does.
+//
+// The zero value of Builder is an empty vector, ready to use.
+type Builder[T any] struct {
+	root  *vecNode[T] // nil if empty
+	count int
+	shift uint // bits of index consumed above the leaf level
+}
+
+// builderFanoutBits is the number of index bits consumed per trie level.
+// Each internal node therefore has up to 1<<builderFanoutBits children,
+// giving O(log n) depth with a small constant (base 32).
+const builderFanoutBits = 5
+const builderFanout = 1 << builderFanoutBits
+const builderFanoutMask = builderFanout - 1
+
+// vecNode is a node of the trie backing Builder. It is either a leaf, with
+// values populated and children nil, or an internal node, with children
+// populated and values nil. Nodes are never mutated after construction,
+// which is what makes sharing them between Builder versions safe.
+type vecNode[T any] struct {
+	values   []T
+	children []*vecNode[T]
+}
+
+// BuilderOf returns a Builder containing the elements of x, in order.
+func BuilderOf[T any](x []T) Builder[T] {
+	var b Builder[T]
+	for _, v := range x {
+		b = b.WithAppended(v)
+	}
+	return b
+}
+
+// Len returns the number of elements in b.
+func (b Builder[T]) Len() int { return b.count }
+
+// At returns the element at index i of b.
+func (b Builder[T]) At(i int) T {
+	n := b.root
+	for shift := b.shift; shift > 0; shift -= builderFanoutBits {
+		n = n.children[(i>>shift)&builderFanoutMask]
+	}
+	return n.values[i&builderFanoutMask]
+}
+
+// WithAppended returns a new Builder with v appended, sharing all of b's
+// structure that didn't need to change.
+func (b Builder[T]) WithAppended(v T) Builder[T] {
+	i := b.count
+	if b.root == nil {
+		return Builder[T]{root: &vecNode[T]{values: []T{v}}, count: 1}
+	}
+	if capacity := 1 << (b.shift + builderFanoutBits); i == capacity {
+		newRoot := &vecNode[T]{children: []*vecNode[T]{b.root}}
+		newShift := b.shift + builderFanoutBits
+		return Builder[T]{root: vecInsert(newRoot, newShift, i, v), count: i + 1, shift: newShift}
+	}
+	return Builder[T]{root: vecInsert(b.root, b.shift, i, v), count: i + 1, shift: b.shift}
+}
+
+// vecInsert returns a copy of the path from n down to the slot for index i,
+// with v written into that slot, sharing every other subtree of n.
+func vecInsert[T any](n *vecNode[T], shift uint, i int, v T) *vecNode[T] {
+	if shift == 0 {
+		values := append(append([]T(nil), n.values...), v)
+		return &vecNode[T]{values: values}
+	}
+	idx := (i >> shift) & builderFanoutMask
+	children := make([]*vecNode[T], len(n.children), idx+1)
+	copy(children, n.children)
+	for len(children) <= idx {
+		children = append(children, nil)
+	}
+	child := children[idx]
+	if child == nil {
+		child = &vecNode[T]{}
+	}
+	children[idx] = vecInsert(child, shift-builderFanoutBits, i, v)
+	return &vecNode[T]{children: children}
+}
+
+// WithReplaced returns a new Builder with the element at index i replaced
+// by v, sharing all of b's structure that didn't need to change.
+//
+// It panics if i is out of range.
+func (b Builder[T]) WithReplaced(i int, v T) Builder[T] {
+	if i < 0 || i >= b.count {
+		panic("views.Builder.WithReplaced: index out of range")
+	}
+	return Builder[T]{root: vecReplace(b.root, b.shift, i, v), count: b.count, shift: b.shift}
+}
+
+func vecReplace[T any](n *vecNode[T], shift uint, i int, v T) *vecNode[T] {
+	if shift == 0 {
+		values := append([]T(nil), n.values...)
+		values[i&builderFanoutMask] = v
+		return &vecNode[T]{values: values}
+	}
+	idx := (i >> shift) & builderFanoutMask
+	children := append([]*vecNode[T](nil), n.children...)
+	children[idx] = vecReplace(children[idx], shift-builderFanoutBits, i, v)
+	return &vecNode[T]{children: children}
+}
+
+// Freeze returns a Slice exposing b's elements through the usual read-only
+// view interface.
+//
+// Freeze is O(n): it walks the whole trie to build a fresh slice. Call it
+// once after a batch of With* calls, not after each one, or the O(log n)
+// benefit of incremental updates is lost to a full copy on every delta.
+func (b Builder[T]) Freeze() Slice[T] {
+	out := make([]T, 0, b.count)
+	if b.root != nil {
+		out = vecAppendTo(b.root, out)
+	}
+	return SliceOf(out)
+}
+
+func vecAppendTo[T any](n *vecNode[T], dst []T) []T {
+	if n.children == nil {
+		return append(dst, n.values...)
+	}
+	for _, c := range n.children {
+		if c != nil {
+			dst = vecAppendTo(c, dst)
+		}
+	}
+	return dst
+}
+
+// MapBuilder is a persistent, copy-on-write map. Each With* method returns a
+// new MapBuilder in O(log n) time (for a roughly balanced key insertion
+// order), sharing every subtree untouched by the change with the receiver.
+//
+// Unlike Builder, MapBuilder is not usable as a zero value: it needs a less
+// func to order keys, so construct one with NewMapBuilder or MapBuilderOf.
+//
+// MapBuilder is backed by a plain binary search tree, not a balanced one: it
+// does not rebalance on insert or delete, so a pathological insertion order
+// (e.g. already-sorted keys inserted one at a time) degrades to O(n) depth.
+// This is fine for the random-ish peer/route keys it's meant for; a
+// self-balancing variant can be layered in later if a caller needs the
+// worst-case guarantee.
+type MapBuilder[K comparable, V any] struct {
+	root *mapNode[K, V]
+	size int
+	less func(K, K) bool
+}
+
+type mapNode[K comparable, V any] struct {
+	key         K
+	val         V
+	left, right *mapNode[K, V]
+}
+
+// NewMapBuilder returns an empty MapBuilder that orders keys using less.
+func NewMapBuilder[K comparable, V any](less func(K, K) bool) MapBuilder[K, V] {
+	return MapBuilder[K, V]{less: less}
+}
+
+// MapBuilderOf returns a MapBuilder containing the entries of m, ordering
+// keys using less.
+func MapBuilderOf[K comparable, V any](m map[K]V, less func(K, K) bool) MapBuilder[K, V] {
+	b := NewMapBuilder[K, V](less)
+	for k, v := range m {
+		b = b.WithSet(k, v)
+	}
+	return b
+}
+
+// Len returns the number of entries in b.
+func (b MapBuilder[K, V]) Len() int { return b.size }
+
+// Get returns the value for k and whether it was present.
+func (b MapBuilder[K, V]) Get(k K) (V, bool) {
+	n := b.root
+	for n != nil {
+		switch {
+		case b.less(k, n.key):
+			n = n.left
+		case b.less(n.key, k):
+			n = n.right
+		default:
+			return n.val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+// WithSet returns a new MapBuilder with k set to v, sharing all of b's
+// structure that didn't need to change.
+func (b MapBuilder[K, V]) WithSet(k K, v V) MapBuilder[K, V] {
+	root, grew := mapInsert(b.root, b.less, k, v)
+	size := b.size
+	if grew {
+		size++
+	}
+	return MapBuilder[K, V]{root: root, size: size, less: b.less}
+}
+
+func mapInsert[K comparable, V any](n *mapNode[K, V], less func(K, K) bool, k K, v V) (_ *mapNode[K, V], grew bool) {
+	if n == nil {
+		return &mapNode[K, V]{key: k, val: v}, true
+	}
+	switch {
+	case less(k, n.key):
+		left, grew := mapInsert(n.left, less, k, v)
+		return &mapNode[K, V]{key: n.key, val: n.val, left: left, right: n.right}, grew
+	case less(n.key, k):
+		right, grew := mapInsert(n.right, less, k, v)
+		return &mapNode[K, V]{key: n.key, val: n.val, left: n.left, right: right}, grew
+	default:
+		return &mapNode[K, V]{key: k, val: v, left: n.left, right: n.right}, false
+	}
+}
+
+// WithDeleted returns a new MapBuilder with k removed, sharing all of b's
+// structure that didn't need to change. It is a no-op if k is not present.
+func (b MapBuilder[K, V]) WithDeleted(k K) MapBuilder[K, V] {
+	root, shrank := mapDelete(b.root, b.less, k)
+	size := b.size
+	if shrank {
+		size--
+	}
+	return MapBuilder[K, V]{root: root, size: size, less: b.less}
+}
+
+func mapDelete[K comparable, V any](n *mapNode[K, V], less func(K, K) bool, k K) (_ *mapNode[K, V], shrank bool) {
+	if n == nil {
+		return nil, false
+	}
+	switch {
+	case less(k, n.key):
+		left, shrank := mapDelete(n.left, less, k)
+		return &mapNode[K, V]{key: n.key, val: n.val, left: left, right: n.right}, shrank
+	case less(n.key, k):
+		right, shrank := mapDelete(n.right, less, k)
+		return &mapNode[K, V]{key: n.key, val: n.val, left: n.left, right: right}, shrank
+	default:
+		switch {
+		case n.left == nil:
+			return n.right, true
+		case n.right == nil:
+			return n.left, true
+		default:
+			succ := n.right
+			for succ.left != nil {
+				succ = succ.left
+			}
+			right, _ := mapDelete(n.right, less, succ.key)
+			return &mapNode[K, V]{key: succ.key, val: succ.val, left: n.left, right: right}, true
+		}
+	}
+}
+
+// Freeze returns a Map exposing b's entries through the usual read-only
+// view interface.
+//
+// Freeze is O(n): it walks the whole tree to build a fresh map. Call it
+// once after a batch of With* calls, not after each one, or the O(log n)
+// benefit of incremental updates is lost to a full copy on every delta.
+func (b MapBuilder[K, V]) Freeze() Map[K, V] {
+	m := make(map[K]V, b.size)
+	mapCollect(b.root, m)
+	return Map[K, V]{m}
+}
+
+func mapCollect[K comparable, V any](n *mapNode[K, V], dst map[K]V) {
+	if n == nil {
+		return
+	}
+	mapCollect(n.left, dst)
+	dst[n.key] = n.val
+	mapCollect(n.right, dst)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2022 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package views
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBuilderAppendAndAt(t *testing.T) {
+	const n = 2000 // several levels deep at the fanout of 32
+	var b Builder[int]
+	for i := 0; i < n; i++ {
+		b = b.WithAppended(i)
+	}
+	if b.Len() != n {
+		t.Fatalf("Len = %d, want %d", b.Len(), n)
+	}
+	for i := 0; i < n; i++ {
+		if got := b.At(i); got != i {
+			t.Fatalf("At(%d) = %d, want %d", i, got, i)
+		}
+	}
+}
+
+func TestBuilderWithReplacedShares(t *testing.T) {
+	var b Builder[int]
+	for i := 0; i < 200; i++ {
+		b = b.WithAppended(i)
+	}
+	b2 := b.WithReplaced(150, 9999)
+	if got := b2.At(150); got != 9999 {
+		t.Errorf("b2.At(150) = %d, want 9999", got)
+	}
+	if got := b.At(150); got != 150 {
+		t.Errorf("original b.At(150) = %d, want 150 (mutated!)", got)
+	}
+	for i := 0; i < 200; i++ {
+		if i == 150 {
+			continue
+		}
+		if b.At(i) != b2.At(i) {
+			t.Errorf("unrelated index %d diverged: b=%d b2=%d", i, b.At(i), b2.At(i))
+		}
+	}
+}
+
+func TestBuilderWithReplacedOutOfRange(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for out-of-range index")
+		}
+	}()
+	var b Builder[int]
+	b = b.WithAppended(1)
+	b.WithReplaced(5, 2)
+}
+
+func TestBuilderFreeze(t *testing.T) {
+	b := BuilderOf([]string{"a", "b", "c"})
+	s := b.Freeze()
+	if s.Len() != 3 || s.At(0) != "a" || s.At(2) != "c" {
+		t.Errorf("Freeze = %v", s.AsSlice())
+	}
+}
+
+func TestMapBuilderSetGetDelete(t *testing.T) {
+	less := func(a, b string) bool { return a < b }
+	mb := NewMapBuilder[string, int](less)
+	for i := 0; i < 200; i++ {
+		mb = mb.WithSet(randKey(i), i)
+	}
+	if mb.Len() != 200 {
+		t.Fatalf("Len = %d, want 200", mb.Len())
+	}
+
+	k := randKey(100)
+	mb2 := mb.WithDeleted(k)
+	if mb2.Len() != 199 {
+		t.Errorf("after delete, Len = %d, want 199", mb2.Len())
+	}
+	if _, ok := mb2.Get(k); ok {
+		t.Errorf("Get(%q) found after delete", k)
+	}
+	if v, ok := mb.Get(k); !ok || v != 100 {
+		t.Errorf("original mb mutated by delete: Get(%q) = (%d, %v)", k, v, ok)
+	}
+
+	// Overwriting an existing key replaces the value without growing size.
+	mb3 := mb.WithSet(k, -1)
+	if mb3.Len() != mb.Len() {
+		t.Errorf("overwrite changed Len: got %d, want %d", mb3.Len(), mb.Len())
+	}
+	if v, ok := mb3.Get(k); !ok || v != -1 {
+		t.Errorf("Get(%q) after overwrite = (%d, %v), want (-1, true)", k, v, ok)
+	}
+}
+
+func TestMapBuilderDeleteMissingIsNoop(t *testing.T) {
+	less := func(a, b string) bool { return a < b }
+	mb := NewMapBuilder[string, int](less).WithSet("a", 1)
+	mb2 := mb.WithDeleted("nonexistent")
+	if mb2.Len() != mb.Len() {
+		t.Errorf("deleting a missing key changed Len: got %d, want %d", mb2.Len(), mb.Len())
+	}
+}
+
+// route models a route table entry whose value contains a slice, making it
+// non-comparable in Go's sense. MapBuilder must still support this, since
+// it's the motivating use case (diffing route tables in ipnlocal).
+type route struct {
+	vias []string
+}
+
+func TestMapBuilderNonComparableValue(t *testing.T) {
+	less := func(a, b int) bool { return a < b }
+	mb := NewMapBuilder[int, route](less)
+	mb = mb.WithSet(1, route{vias: []string{"a", "b"}})
+	mb = mb.WithSet(2, route{vias: []string{"c"}})
+	v, ok := mb.Get(1)
+	if !ok || len(v.vias) != 2 {
+		t.Fatalf("Get(1) = (%+v, %v)", v, ok)
+	}
+	m := mb.Freeze()
+	if m.Len() != 2 {
+		t.Fatalf("Freeze len = %d, want 2", m.Len())
+	}
+}
+
+func TestMapBuilderFreeze(t *testing.T) {
+	less := func(a, b string) bool { return a < b }
+	mb := MapBuilderOf(map[string]int{"a": 1, "b": 2}, less)
+	m := mb.Freeze()
+	if m.Len() != 2 || m.Get("a") != 1 || m.Get("b") != 2 {
+		t.Errorf("Freeze produced wrong map: Len=%d a=%d b=%d", m.Len(), m.Get("a"), m.Get("b"))
+	}
+}
+
+func randKey(i int) string {
+	return fmt.Sprintf("key-%04d", i)
+}
@@ -9,7 +9,9 @@ package views
 import (
 	"encoding/json"
 	"errors"
+	"sort"
 
+	"golang.org/x/exp/constraints"
 	"tailscale.com/net/netaddr"
 	"tailscale.com/net/tsaddr"
 )
@@ -88,6 +90,84 @@ func (v SliceView[T, V]) AsSlice() []V {
 	return v.AppendTo(nil)
 }
 
+// MapViewOf returns a MapView for x.
+func MapViewOf[K comparable, T ViewCloner[T, V], V StructView[T]](x map[K]T) MapView[K, T, V] {
+	return MapView[K, T, V]{x}
+}
+
+// MapView is a read-only view of a map of ViewCloner values, such as a
+// struct field typed map[K]*Node where *Node implements
+// ViewCloner[*Node, NodeView]. It is the map analogue of SliceView,
+// avoiding the need for callers to hand-write a MapFnOf wrap func.
+//
+// MapView values are constructed with MapViewOf; tailscale.com/cmd/viewer
+// does not emit MapView wrappers for map fields in generated View methods.
+type MapView[K comparable, T ViewCloner[T, V], V StructView[T]] struct {
+	// ж is the underlying mutable value, named with a hard-to-type
+	// character that looks pointy like a pointer.
+	// It is named distinctively to make you think of how dangerous it is to escape
+	// to callers. You must not let callers be able to mutate it.
+	ж map[K]T
+}
+
+// IsNil reports whether the underlying map is nil.
+func (m MapView[K, T, V]) IsNil() bool { return m.ж == nil }
+
+// Len returns the number of elements in the map.
+func (m MapView[K, T, V]) Len() int { return len(m.ж) }
+
+// Has reports whether k has an entry in the map.
+func (m MapView[K, T, V]) Has(k K) bool {
+	_, ok := m.ж[k]
+	return ok
+}
+
+// At returns a View of the element at key k of the map.
+func (m MapView[K, T, V]) At(k K) V { return m.ж[k].View() }
+
+// GetOk returns a View of the element at key k of the map, and whether k
+// was present.
+func (m MapView[K, T, V]) GetOk(k K) (V, bool) {
+	v, ok := m.ж[k]
+	return v.View(), ok
+}
+
+// Range calls f for every k,v pair in the underlying map.
+// It stops iteration immediately if f returns false.
+func (m MapView[K, T, V]) Range(f MapRangeFn[K, V]) {
+	for k, v := range m.ж {
+		if !f(k, v.View()) {
+			return
+		}
+	}
+}
+
+// AsMap returns a deep-clone of the underlying map.
+func (m MapView[K, T, V]) AsMap() map[K]T {
+	if m.ж == nil {
+		return nil
+	}
+	out := make(map[K]T, len(m.ж))
+	for k, v := range m.ж {
+		out[k] = v.Clone()
+	}
+	return out
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m MapView[K, T, V]) MarshalJSON() ([]byte, error) { return json.Marshal(m.ж) }
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *MapView[K, T, V]) UnmarshalJSON(b []byte) error {
+	if m.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, &m.ж)
+}
+
 // Slice is a read-only accessor for a slice.
 type Slice[T any] struct {
 	// ж is the underlying mutable value, named with a hard-to-type
@@ -335,3 +415,250 @@ func (m MapFn[K, T, V]) Range(f MapRangeFn[K, V]) {
 		}
 	}
 }
+
+// SortedSliceOf returns a SortedSlice for the provided slice, which must
+// already be sorted according to less. It is the caller's responsibility to
+// make sure x is sorted and that T is immutable; SortedSliceOf does not sort
+// or copy x.
+func SortedSliceOf[T any](x []T, less func(T, T) bool) SortedSlice[T] {
+	return SortedSlice[T]{SliceOf(x), less}
+}
+
+// SortedSlice is a read-only accessor for a slice that is maintained in
+// sorted order by the caller, enabling O(log n) lookups via BinarySearch.
+type SortedSlice[T any] struct {
+	ж    Slice[T]
+	less func(T, T) bool
+}
+
+// IsNil reports whether the underlying slice is nil.
+func (v SortedSlice[T]) IsNil() bool { return v.ж.IsNil() }
+
+// Len returns the length of the slice.
+func (v SortedSlice[T]) Len() int { return v.ж.Len() }
+
+// At returns the element at index `i` of the slice.
+func (v SortedSlice[T]) At(i int) T { return v.ж.At(i) }
+
+// AppendTo appends the underlying slice values to dst.
+func (v SortedSlice[T]) AppendTo(dst []T) []T { return v.ж.AppendTo(dst) }
+
+// AsSlice returns a copy of underlying slice.
+func (v SortedSlice[T]) AsSlice() []T { return v.ж.AsSlice() }
+
+// MarshalJSON implements json.Marshaler.
+func (v SortedSlice[T]) MarshalJSON() ([]byte, error) { return v.ж.MarshalJSON() }
+
+// BinarySearch returns the index of target in v and whether it was found,
+// using the slice's less func. It runs in O(log n) time, so v must already
+// be sorted consistently with less.
+func (v SortedSlice[T]) BinarySearch(target T) (index int, found bool) {
+	n := v.Len()
+	i := sort.Search(n, func(i int) bool { return !v.less(v.At(i), target) })
+	if i < n && !v.less(target, v.At(i)) {
+		return i, true
+	}
+	return i, false
+}
+
+// Contains reports whether v contains target.
+//
+// As it runs in O(log n) time, it is preferred over SliceContains for
+// sorted slices.
+func (v SortedSlice[T]) Contains(target T) bool {
+	_, found := v.BinarySearch(target)
+	return found
+}
+
+// Range calls f for every element of v in sorted order. It stops iteration
+// immediately if f returns false.
+func (v SortedSlice[T]) Range(f func(T) (cont bool)) {
+	for i := 0; i < v.Len(); i++ {
+		if !f(v.At(i)) {
+			return
+		}
+	}
+}
+
+// SortedMapOf returns a SortedMap over m. Keys are sorted at construction
+// time so that Range visits them in deterministic, ascending order.
+func SortedMapOf[K constraints.Ordered, V any](m map[K]V) SortedMap[K, V] {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return SortedMap[K, V]{ж: m, keys: keys}
+}
+
+// SortedMap is a view over a map whose values are immutable and whose keys
+// are visited in sorted order by Range, MarshalJSON, and similar. This makes
+// it suitable for stable JSON output and diffing, unlike Map's unordered
+// iteration.
+type SortedMap[K constraints.Ordered, V any] struct {
+	ж    map[K]V
+	keys []K
+}
+
+// Has reports whether k has an entry in the map.
+func (m SortedMap[K, V]) Has(k K) bool {
+	_, ok := m.ж[k]
+	return ok
+}
+
+// IsNil reports whether the underlying map is nil.
+func (m SortedMap[K, V]) IsNil() bool { return m.ж == nil }
+
+// Len returns the number of elements in the map.
+func (m SortedMap[K, V]) Len() int { return len(m.ж) }
+
+// Get returns the element with key k.
+func (m SortedMap[K, V]) Get(k K) V { return m.ж[k] }
+
+// GetOk returns the element with key k and a bool representing whether the
+// key is in map.
+func (m SortedMap[K, V]) GetOk(k K) (V, bool) {
+	v, ok := m.ж[k]
+	return v, ok
+}
+
+// Range calls f for every k,v pair in the underlying map, visiting keys in
+// ascending order. It stops iteration immediately if f returns false.
+func (m SortedMap[K, V]) Range(f MapRangeFn[K, V]) {
+	for _, k := range m.keys {
+		if !f(k, m.ж[k]) {
+			return
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, emitting the map's entries as a
+// JSON object with keys in sorted order.
+func (m SortedMap[K, V]) MarshalJSON() ([]byte, error) {
+	var buf []byte
+	buf = append(buf, '{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		kb, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		// JSON object keys must be strings; re-marshal non-string keys
+		// as a quoted string, matching encoding/json's handling of integer
+		// map keys. Float keys, which encoding/json rejects, are quoted too.
+		if len(kb) == 0 || kb[0] != '"' {
+			kb, err = json.Marshal(string(kb))
+			if err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, kb...)
+		buf = append(buf, ':')
+		vb, err := json.Marshal(m.ж[k])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, vb...)
+	}
+	buf = append(buf, '}')
+	return buf, nil
+}
+
+// SetOf returns a Set containing the elements of x. less is used to order
+// the set for Range and MarshalJSON, the same way callers supply less to
+// SortedSliceOf; it is never consulted by Has or Len.
+func SetOf[T comparable](x []T, less func(T, T) bool) Set[T] {
+	m := make(map[T]struct{}, len(x))
+	for _, v := range x {
+		m[v] = struct{}{}
+	}
+	return Set[T]{m, less}
+}
+
+// Set is a read-only view of a set of comparable values, backed by a
+// map[T]struct{}. It marshals as a sorted JSON array so that it round trips
+// cleanly and diffs stably. T need not be ordered (e.g. it may be a struct
+// like netaddr.IPPrefix): the set only requires comparable for membership,
+// and a caller-supplied less for the sorted operations.
+//
+// The zero value, and a Set produced by UnmarshalJSON, have no less func.
+// Has and Len work fine on such a Set, but Range and MarshalJSON fall back
+// to an arbitrary, non-deterministic order (Go's map iteration order)
+// instead of panicking, since encoding/json.Unmarshaler has no way to pass
+// one in. Call WithLess to attach one, e.g. right after unmarshaling, if
+// Range or MarshalJSON need to produce a stable order.
+type Set[T comparable] struct {
+	ж    map[T]struct{}
+	less func(T, T) bool
+}
+
+// WithLess returns a copy of s that orders Range and MarshalJSON using
+// less. It's most useful to finish constructing a Set after UnmarshalJSON,
+// which can't accept a less func of its own.
+func (s Set[T]) WithLess(less func(T, T) bool) Set[T] {
+	return Set[T]{s.ж, less}
+}
+
+// Has reports whether v is a member of the set.
+func (s Set[T]) Has(v T) bool {
+	_, ok := s.ж[v]
+	return ok
+}
+
+// Len returns the number of elements in the set.
+func (s Set[T]) Len() int { return len(s.ж) }
+
+// Range calls f for every element of the set, in sorted order if s has a
+// less func (see the Set doc comment), or an unspecified order otherwise.
+// It stops iteration immediately if f returns false.
+//
+// As building the sorted order runs in O(n log n) time, Range is best
+// avoided in hot paths that don't need a deterministic order; use Has for
+// O(1) membership tests instead.
+func (s Set[T]) Range(f func(T) (cont bool)) {
+	for _, v := range s.sorted() {
+		if !f(v) {
+			return
+		}
+	}
+}
+
+func (s Set[T]) sorted() []T {
+	out := make([]T, 0, len(s.ж))
+	for v := range s.ж {
+		out = append(out, v)
+	}
+	if s.less != nil {
+		sort.Slice(out, func(i, j int) bool { return s.less(out[i], out[j]) })
+	}
+	return out
+}
+
+// MarshalJSON implements json.Marshaler, emitting the set as a JSON array,
+// sorted if s has a less func (see the Set doc comment).
+func (s Set[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.sorted())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The resulting Set has no less
+// func (see the Set doc comment); call WithLess afterward if Range or
+// MarshalJSON need to produce a stable order.
+func (s *Set[T]) UnmarshalJSON(b []byte) error {
+	if s.ж != nil {
+		return errors.New("already initialized")
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	var x []T
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	s.ж = make(map[T]struct{}, len(x))
+	for _, v := range x {
+		s.ж[v] = struct{}{}
+	}
+	return nil
+}
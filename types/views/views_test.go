@@ -0,0 +1,229 @@
+// Copyright (c) 2022 Tailscale Inc & AUTHORS All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package views
+
+import (
+	"reflect"
+	"testing"
+)
+
+func lessInt(a, b int) bool { return a < b }
+
+func TestSortedSlice(t *testing.T) {
+	ss := SortedSliceOf([]int{1, 3, 5, 7, 9}, lessInt)
+	if ss.Len() != 5 {
+		t.Fatalf("Len = %d, want 5", ss.Len())
+	}
+	for _, tt := range []struct {
+		target int
+		index  int
+		found  bool
+	}{
+		{1, 0, true},
+		{9, 4, true},
+		{5, 2, true},
+		{0, 0, false},
+		{4, 2, false},
+		{10, 5, false},
+	} {
+		i, found := ss.BinarySearch(tt.target)
+		if i != tt.index || found != tt.found {
+			t.Errorf("BinarySearch(%d) = (%d, %v), want (%d, %v)", tt.target, i, found, tt.index, tt.found)
+		}
+		if got := ss.Contains(tt.target); got != tt.found {
+			t.Errorf("Contains(%d) = %v, want %v", tt.target, got, tt.found)
+		}
+	}
+
+	var got []int
+	ss.Range(func(v int) bool {
+		got = append(got, v)
+		return true
+	})
+	if want := []int{1, 3, 5, 7, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range = %v, want %v", got, want)
+	}
+}
+
+func TestSortedMap(t *testing.T) {
+	sm := SortedMapOf(map[string]int{"z": 1, "a": 2, "m": 3})
+	if sm.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", sm.Len())
+	}
+	var keys []string
+	sm.Range(func(k string, v int) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if want := []string{"a", "m", "z"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Range keys = %v, want %v", keys, want)
+	}
+
+	b, err := sm.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"a":2,"m":3,"z":1}`; got != want {
+		t.Errorf("MarshalJSON = %s, want %s", got, want)
+	}
+}
+
+// point is a comparable, non-ordered struct type, standing in for things
+// like netaddr.IPPrefix that Set needs to support.
+type point struct{ X, Y int }
+
+func lessPoint(a, b point) bool {
+	if a.X != b.X {
+		return a.X < b.X
+	}
+	return a.Y < b.Y
+}
+
+func TestSet(t *testing.T) {
+	s := SetOf([]point{{1, 2}, {0, 0}, {1, 1}}, lessPoint)
+	if s.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", s.Len())
+	}
+	if !s.Has(point{1, 2}) || s.Has(point{9, 9}) {
+		t.Errorf("Has returned wrong membership")
+	}
+
+	var got []point
+	s.Range(func(p point) bool {
+		got = append(got, p)
+		return true
+	})
+	want := []point{{0, 0}, {1, 1}, {1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Range = %v, want %v", got, want)
+	}
+
+	b, err := s.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `[{"X":0,"Y":0},{"X":1,"Y":1},{"X":1,"Y":2}]`; got != want {
+		t.Errorf("MarshalJSON = %s, want %s", got, want)
+	}
+
+	var s2 Set[point]
+	if err := s2.UnmarshalJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if s2.Len() != 3 || !s2.Has(point{1, 1}) {
+		t.Errorf("round-tripped set = %+v, want 3 elements including {1,1}", s2)
+	}
+
+	// A Set fresh off UnmarshalJSON has no less func. Range and MarshalJSON
+	// must not panic on it, even though their order is unspecified until
+	// WithLess is called.
+	var gotUnordered []point
+	s2.Range(func(p point) bool {
+		gotUnordered = append(gotUnordered, p)
+		return true
+	})
+	if len(gotUnordered) != 3 {
+		t.Errorf("Range on unmarshaled set got %d elements, want 3", len(gotUnordered))
+	}
+	if _, err := s2.MarshalJSON(); err != nil {
+		t.Errorf("MarshalJSON on unmarshaled set: %v", err)
+	}
+
+	s3 := s2.WithLess(lessPoint)
+	b2, err := s3.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b2), `[{"X":0,"Y":0},{"X":1,"Y":1},{"X":1,"Y":2}]`; got != want {
+		t.Errorf("MarshalJSON after WithLess = %s, want %s", got, want)
+	}
+}
+
+// mapViewTestItem and mapViewTestItemView stand in for a generated
+// *Node/NodeView pair, exercising MapView the way cmd/viewer output would.
+type mapViewTestItem struct {
+	Name string
+}
+
+func (v *mapViewTestItem) Clone() *mapViewTestItem {
+	if v == nil {
+		return nil
+	}
+	v2 := *v
+	return &v2
+}
+
+func (v *mapViewTestItem) View() mapViewTestItemView {
+	return mapViewTestItemView{v}
+}
+
+type mapViewTestItemView struct {
+	ж *mapViewTestItem
+}
+
+func (v mapViewTestItemView) Valid() bool                { return v.ж != nil }
+func (v mapViewTestItemView) AsStruct() *mapViewTestItem { return v.ж.Clone() }
+func (v mapViewTestItemView) Name() string               { return v.ж.Name }
+
+func TestMapView(t *testing.T) {
+	m := map[string]*mapViewTestItem{
+		"a": {Name: "alpha"},
+		"b": {Name: "bravo"},
+	}
+	mv := MapViewOf(m)
+
+	if mv.IsNil() {
+		t.Error("IsNil = true, want false")
+	}
+	if mv.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", mv.Len())
+	}
+	if !mv.Has("a") || mv.Has("z") {
+		t.Errorf("Has returned wrong membership")
+	}
+	if got := mv.At("a").Name(); got != "alpha" {
+		t.Errorf("At(%q).Name() = %q, want alpha", "a", got)
+	}
+	if v, ok := mv.GetOk("b"); !ok || v.Name() != "bravo" {
+		t.Errorf("GetOk(%q) = (%v, %v), want (bravo view, true)", "b", v, ok)
+	}
+	if _, ok := mv.GetOk("z"); ok {
+		t.Errorf("GetOk(%q) found a missing key", "z")
+	}
+
+	seen := map[string]string{}
+	mv.Range(func(k string, v mapViewTestItemView) bool {
+		seen[k] = v.Name()
+		return true
+	})
+	if want := map[string]string{"a": "alpha", "b": "bravo"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("Range visited %v, want %v", seen, want)
+	}
+
+	clone := mv.AsMap()
+	if len(clone) != 2 || clone["a"].Name != "alpha" || clone["b"].Name != "bravo" {
+		t.Errorf("AsMap = %+v", clone)
+	}
+	clone["a"].Name = "mutated"
+	if m["a"].Name != "alpha" {
+		t.Errorf("mutating AsMap's result affected the original: %q", m["a"].Name)
+	}
+
+	b, err := mv.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mv2 MapView[string, *mapViewTestItem, mapViewTestItemView]
+	if err := mv2.UnmarshalJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	if mv2.Len() != 2 || mv2.At("b").Name() != "bravo" {
+		t.Errorf("round-tripped MapView = %+v", mv2)
+	}
+	if err := mv2.UnmarshalJSON(b); err == nil {
+		t.Error("second UnmarshalJSON into an initialized MapView should have errored")
+	}
+}